@@ -0,0 +1,72 @@
+package asyncio
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/bufpool"
+)
+
+// partialWriter fails once its Write calls have delivered failAfter bytes in
+// total, writing only the bytes up to that point before erroring; every
+// subsequent call succeeds. It's used to simulate a write that fails
+// partway through.
+type partialWriter struct {
+	mu        sync.Mutex
+	written   []byte
+	failAfter int
+	failed    bool
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.failed && len(w.written)+len(p) > w.failAfter {
+		n := w.failAfter - len(w.written)
+		if n < 0 {
+			n = 0
+		}
+		w.written = append(w.written, p[:n]...)
+		w.failed = true
+		return n, errors.New("boom")
+	}
+	w.written = append(w.written, p...)
+	return len(p), nil
+}
+
+// TestTickedBuffer_WriteBufferRetryDoesNotDuplicate checks that retrying a
+// buffer write that failed partway through resumes after the bytes already
+// delivered, instead of resending the whole buffer and duplicating them.
+func TestTickedBuffer_WriteBufferRetryDoesNotDuplicate(t *testing.T) {
+	w := &partialWriter{failAfter: 5}
+	tb := NewTickedBuffer(w, 16, 2, 1, time.Millisecond,
+		SetRetryPolicy(RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+
+	buf := bufpool.NewBuffer(16)
+	buf.Write([]byte("abcdefghij"))
+
+	tb.wg.Add(1)
+	tb.writeBuffer(buf)
+
+	if want := "abcdefghij"; string(w.written) != want {
+		t.Fatalf("written = %q, want %q", w.written, want)
+	}
+}
+
+// TestTickedBuffer_WriteDirectRetryDoesNotDuplicate is
+// TestTickedBuffer_WriteBufferRetryDoesNotDuplicate's counterpart for the
+// oversized-write bypass path, which has its own retry loop.
+func TestTickedBuffer_WriteDirectRetryDoesNotDuplicate(t *testing.T) {
+	w := &partialWriter{failAfter: 5}
+	tb := NewTickedBuffer(w, 16, 2, 1, time.Millisecond,
+		SetRetryPolicy(RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+
+	tb.wg.Add(1)
+	tb.writeDirect([]byte("abcdefghij"))
+
+	if want := "abcdefghij"; string(w.written) != want {
+		t.Fatalf("written = %q, want %q", w.written, want)
+	}
+}
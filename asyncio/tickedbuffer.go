@@ -2,13 +2,15 @@
 package asyncio
 
 import (
+	"context"
 	"errors"
 	"io"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/travelgateX/go-io/asyncio/internal"
+	"github.com/travelgateX/go-io/bufpool"
+	"github.com/travelgateX/go-io/internal/retry"
 )
 
 var _ io.WriteCloser = &TickedBuffer{}
@@ -21,9 +23,9 @@ var _ io.WriteCloser = &TickedBuffer{}
 type TickedBuffer struct {
 	// buffer write operations are potentially slow, a new buffer
 	// takes the stage when the current is sent to write
-	pool *internal.BufferPool
+	pool bufpool.Pool
 	// current buffer
-	buf *internal.Buffer
+	buf *bufpool.Buffer
 
 	data   chan []byte
 	closed bool
@@ -34,6 +36,15 @@ type TickedBuffer struct {
 	// done channel won't be closed until wg is done
 	wg sync.WaitGroup
 
+	// retryPolicy governs how a failed flush is retried before giving up,
+	// nil means a failed flush is never retried
+	retryPolicy *RetryPolicy
+	// onError is invoked once a flush gives up retrying
+	onError func(err error, payload []byte, attempt int)
+	// shutdownCtx bounds how long in-flight retries are allowed to keep
+	// sleeping once CloseContext is called
+	shutdownCtx atomic.Value
+
 	stats Stats
 	// client variables
 	Writer        io.Writer // writer can be changed at any time
@@ -44,15 +55,21 @@ type TickedBuffer struct {
 // NewTickedBuffer wraps a writer with a buffer layer that will write to an underlying writer
 // when its buffer is full or a timer ticks
 // Call Close to free goroutines, Close blocks until all buffers flush, calling Close and then Write won't panic
-func NewTickedBuffer(w io.Writer, bufSize, poolSize, queueSize int, flushInterval time.Duration) *TickedBuffer {
+func NewTickedBuffer(w io.Writer, bufSize, poolSize, queueSize int, flushInterval time.Duration, options ...TickedBufferOption) *TickedBuffer {
 	tb := &TickedBuffer{
-		pool:          internal.NewBufferPool(poolSize, bufSize),
 		data:          make(chan []byte, queueSize),
 		done:          make(chan struct{}),
 		flushInterval: flushInterval,
 		Writer:        w,
 		size:          bufSize,
 	}
+	for _, o := range options {
+		o(tb)
+	}
+	if tb.pool == nil {
+		tb.pool = bufpool.NewChannelPool(poolSize, bufSize)
+	}
+	tb.shutdownCtx.Store(context.Background())
 
 	// start listening in background
 	go tb.listen()
@@ -60,6 +77,42 @@ func NewTickedBuffer(w io.Writer, bufSize, poolSize, queueSize int, flushInterva
 	return tb
 }
 
+// TickedBufferOption are optional configurations used on a TickedBuffer instantiation
+type TickedBufferOption func(*TickedBuffer)
+
+// SetBufferPool swaps the default channel-backed buffer pool for a custom
+// implementation, e.g. to share one pool across many TickedBuffer instances,
+// or bufpool.NopPool{} to measure pooling overhead against plain allocation.
+func SetBufferPool(p bufpool.Pool) TickedBufferOption {
+	return func(tb *TickedBuffer) {
+		tb.pool = p
+	}
+}
+
+// SetRetryPolicy makes a failed flush retry with the given backoff instead
+// of being given up on after a single attempt. The buffer being flushed is
+// held, not returned to the pool, until the retry succeeds or is given up.
+func SetRetryPolicy(p RetryPolicy) TickedBufferOption {
+	return func(tb *TickedBuffer) {
+		tb.retryPolicy = &p
+	}
+}
+
+// SetOnError registers a callback invoked when a flush gives up retrying,
+// with the payload that couldn't be written, so callers can persist it or
+// alert instead of losing it silently.
+func SetOnError(f func(err error, payload []byte, attempt int)) TickedBufferOption {
+	return func(tb *TickedBuffer) {
+		tb.onError = f
+	}
+}
+
+// RetryPolicy configures the exponential backoff used to retry a failed
+// flush. The delay before the n-th retry is InitialInterval * Multiplier^n,
+// capped at MaxInterval. Retrying stops once MaxElapsedTime has passed since
+// the first attempt, or once Classify says the error isn't worth retrying.
+type RetryPolicy = retry.RetryPolicy
+
 // ErrBlockingWrite is returned when trying to write on a full channel
 var ErrBlockingWrite = errors.New("write was blocking")
 
@@ -84,6 +137,14 @@ func (tb *TickedBuffer) Write(p []byte) (int, error) {
 // Close is concurrent safe and blocks until the remaining data
 // in buffer is flushed
 func (tb *TickedBuffer) Close() error {
+	return tb.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but only waits for in-flight retries until ctx
+// is done; buffers still being retried past that deadline are handed to
+// OnError instead of being lost to a goroutine that never stops retrying.
+func (tb *TickedBuffer) CloseContext(ctx context.Context) error {
+	tb.shutdownCtx.Store(ctx)
 	tb.closed = true
 	<-tb.done
 	return nil
@@ -110,13 +171,7 @@ func (tb *TickedBuffer) listen() {
 			// underlying writer to avoid an unnecessary copy
 			if len(p) >= tb.size {
 				tb.wg.Add(1)
-				go func(p []byte) {
-					_, err := tb.Writer.Write(p)
-					if err != nil {
-						atomic.AddInt32(&tb.stats.FlushErrors, 1)
-					}
-					tb.wg.Done()
-				}(p)
+				go tb.writeDirect(p)
 				flushedBetweenTicks = true
 			} else {
 				// if data is going to outbound the current
@@ -145,8 +200,8 @@ func (tb *TickedBuffer) listen() {
 	close(tb.done)
 }
 
-func (tb *TickedBuffer) getBuffer() *internal.Buffer {
-	b, alloc := tb.pool.Get()
+func (tb *TickedBuffer) getBuffer() *bufpool.Buffer {
+	b, alloc := tb.pool.Get(tb.size)
 	if alloc {
 		tb.stats.BufferAllocs++
 	}
@@ -164,14 +219,96 @@ func (tb *TickedBuffer) flush() {
 	buf := tb.buf
 	tb.buf = tb.getBuffer()
 	tb.wg.Add(1)
-	go func() {
-		_, err := buf.WriteTo(tb.Writer)
-		if err != nil {
-			atomic.AddInt32(&tb.stats.FlushErrors, 1)
+	go tb.writeBuffer(buf)
+}
+
+// writeDirect writes p straight to the underlying writer, bypassing the
+// buffer pool, for writes too big to fit a single buffer. On failure it
+// retries with backoff the same way writeBuffer does, only resending the
+// part of p not yet confirmed written, and reports whatever's left to
+// onError once it gives up instead of dropping it silently.
+func (tb *TickedBuffer) writeDirect(p []byte) {
+	defer tb.wg.Done()
+
+	var sent int
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		n, err := tb.Writer.Write(p[sent:])
+		sent += n
+		if err == nil && sent != len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			return
 		}
-		tb.pool.Put(buf)
-		tb.wg.Done()
-	}()
+		atomic.AddInt32(&tb.stats.FlushErrors, 1)
+
+		if tb.retryPolicy == nil || !tb.retryPolicy.ShouldRetry(err, time.Since(start)) {
+			if tb.onError != nil {
+				tb.onError(err, p[sent:], attempt)
+			}
+			return
+		}
+
+		ctx := tb.shutdownCtx.Load().(context.Context)
+		select {
+		case <-time.After(tb.retryPolicy.Backoff(attempt)):
+		case <-ctx.Done():
+			if tb.onError != nil {
+				tb.onError(err, p[sent:], attempt)
+			}
+			return
+		}
+	}
+}
+
+// writeBuffer writes buf to the underlying writer. On failure, and if a
+// RetryPolicy was set, it keeps retrying with backoff instead of dropping
+// the buffer: it's only returned to the pool once the write succeeds or is
+// given up on, either because the policy says so or because CloseContext's
+// deadline has passed. A retry only resends the part of buf that a prior
+// attempt didn't already confirm written, since buf.WriteTo doesn't track a
+// resume offset itself and would otherwise resend bytes already delivered
+// to the sink.
+func (tb *TickedBuffer) writeBuffer(buf *bufpool.Buffer) {
+	defer tb.wg.Done()
+
+	var sent int
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		n, err := tb.Writer.Write(buf.Bytes()[sent:])
+		sent += n
+		if err == nil && sent != buf.Buffered() {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			break
+		}
+		atomic.AddInt32(&tb.stats.FlushErrors, 1)
+
+		if tb.retryPolicy == nil || !tb.retryPolicy.ShouldRetry(err, time.Since(start)) {
+			tb.giveUp(buf, sent, err, attempt)
+			return
+		}
+
+		ctx := tb.shutdownCtx.Load().(context.Context)
+		select {
+		case <-time.After(tb.retryPolicy.Backoff(attempt)):
+		case <-ctx.Done():
+			tb.giveUp(buf, sent, err, attempt)
+			return
+		}
+	}
+	tb.pool.Put(buf)
+}
+
+// giveUp reports the still-unsent part of buf to OnError, if set, and
+// returns it to the pool; the caller is done retrying it.
+func (tb *TickedBuffer) giveUp(buf *bufpool.Buffer, sent int, err error, attempt int) {
+	if tb.onError != nil {
+		tb.onError(err, append([]byte(nil), buf.Bytes()[sent:]...), attempt)
+	}
+	tb.pool.Put(buf)
 }
 
 // Stats contains performance statistics, some of the settings for this writer
@@ -1,4 +1,4 @@
-package internal
+package bufpool
 
 import (
 	"fmt"
@@ -16,7 +16,8 @@ type Buffer struct {
 	n   int
 }
 
-func newBuffer(size int) *Buffer {
+// NewBuffer allocates a Buffer with the given capacity.
+func NewBuffer(size int) *Buffer {
 	return &Buffer{buf: make([]byte, size)}
 }
 
@@ -45,6 +46,12 @@ func (b *Buffer) Write(p []byte) (int, error) {
 	return n, nil
 }
 
+// Bytes returns the buffered data, the slice is only valid until the next
+// Reset or Write call.
+func (b *Buffer) Bytes() []byte {
+	return b.buf[:b.n]
+}
+
 // WriteTo flushes all the buffer data into a writer
 func (b *Buffer) WriteTo(w io.Writer) (n int64, err error) {
 	m, err := w.Write(b.buf[:b.n])
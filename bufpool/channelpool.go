@@ -0,0 +1,51 @@
+package bufpool
+
+var _ Pool = &ChannelPool{}
+
+// ChannelPool is a Pool of buffers that can't be garbage collected while
+// retained. https://golang.org/doc/effective_go.html#leaky_buffer
+type ChannelPool struct {
+	free   chan *Buffer
+	bufcap int
+}
+
+// NewChannelPool instances a ChannelPool with 'size' buffers,
+// buffers will be allocated with a 'bufcap' capacity
+func NewChannelPool(size, bufcap int) *ChannelPool {
+	return &ChannelPool{
+		free:   make(chan *Buffer, size),
+		bufcap: bufcap,
+	}
+}
+
+// Get returns an available buffer, if any, a new one will be allocated.
+// Returns a bool indicating if an allocation happened. A size that doesn't
+// match the pool's bufcap is always allocated fresh and never pooled.
+func (p *ChannelPool) Get(size int) (*Buffer, bool) {
+	if size != p.bufcap {
+		return NewBuffer(size), true
+	}
+	select {
+	case buf := <-p.free:
+		// got one
+		return buf, false
+	default:
+		// there aren't free buffers, allocate new one
+		return NewBuffer(p.bufcap), true
+	}
+}
+
+// Put returns a buffer, its dropped on the floor if the pool is full or the
+// buffer's capacity doesn't match the pool's
+func (p *ChannelPool) Put(b *Buffer) {
+	b.Reset()
+	if cap(b.buf) != p.bufcap {
+		return
+	}
+	select {
+	case p.free <- b:
+		// reuse buffer
+	default:
+		// free list full; drop
+	}
+}
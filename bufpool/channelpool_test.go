@@ -0,0 +1,35 @@
+package bufpool
+
+import "testing"
+
+func TestChannelPool_ReusesPutBuffers(t *testing.T) {
+	p := NewChannelPool(1, 8)
+
+	b1, alloc := p.Get(8)
+	if !alloc {
+		t.Fatalf("first Get: alloc = false, want true (pool starts empty)")
+	}
+	p.Put(b1)
+
+	b2, alloc := p.Get(8)
+	if alloc {
+		t.Fatalf("second Get: alloc = true, want false (should reuse b1)")
+	}
+	if b2 != b1 {
+		t.Fatalf("second Get returned a different buffer than the one Put")
+	}
+}
+
+func TestChannelPool_SizeMismatchNeverPooled(t *testing.T) {
+	p := NewChannelPool(1, 8)
+
+	b, alloc := p.Get(4)
+	if !alloc {
+		t.Fatalf("Get with mismatched size: alloc = false, want true")
+	}
+	p.Put(b)
+
+	if _, alloc := p.Get(8); !alloc {
+		t.Fatalf("Get after Put of a mismatched-size buffer: alloc = false, want true (it shouldn't have been pooled)")
+	}
+}
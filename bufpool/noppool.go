@@ -0,0 +1,16 @@
+package bufpool
+
+var _ Pool = NopPool{}
+
+// NopPool is a Pool that always allocates a fresh Buffer and never retains
+// one on Put. Useful to benchmark pooling overhead against a plain
+// allocator in a given workload.
+type NopPool struct{}
+
+// Get always allocates a new buffer.
+func (NopPool) Get(size int) (*Buffer, bool) {
+	return NewBuffer(size), true
+}
+
+// Put drops b on the floor.
+func (NopPool) Put(*Buffer) {}
@@ -0,0 +1,15 @@
+// Package bufpool provides a pluggable pool of reusable byte Buffers for
+// writers that stage data before flushing it to an underlying io.Writer,
+// such as syncio.Buffer and asyncio.TickedBuffer. Promoting the pool to an
+// interface lets those writers share a single pool across many instances,
+// or swap pooling for NopPool to measure contention against GC pressure.
+package bufpool
+
+// Pool hands out and reclaims Buffers.
+type Pool interface {
+	// Get returns a buffer with 'size' capacity. If none are free, a new
+	// one is allocated; the bool reports whether that happened.
+	Get(size int) (*Buffer, bool)
+	// Put returns a buffer to the pool once the caller is done with it.
+	Put(*Buffer)
+}
@@ -0,0 +1,238 @@
+// Package httpwriter holds the shared implementation behind httpio.HTTP and
+// netio.HTTPWriter, which are both type aliases of Writer: the two packages
+// used to carry byte-identical copies of this logic.
+package httpwriter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Writer gives io.Write methods to a http Client
+type Writer struct {
+	Client *http.Client
+	Method string
+	URL    string
+	Header http.Header
+
+	// RetryPolicy, if set, retries a failed write with backoff instead of
+	// giving up after a single attempt. A Retry-After response header, when
+	// present, takes precedence over the computed backoff.
+	RetryPolicy *RetryPolicy
+
+	// Context bounds inflight writes and retries, e.g. to cancel them during
+	// shutdown. Defaults to context.Background.
+	Context context.Context
+
+	// reqPool pools the *http.Request and its bytes.Reader for this Writer;
+	// it must not be shared between Writers pointed at different URLs.
+	reqPool sync.Pool
+}
+
+// HTTPError is returned when the endpoint responds with a non-2xx status,
+// so that buffered writers see it as a failed write and can retry it.
+type HTTPError struct {
+	Status int
+	Body   []byte
+	Header http.Header
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("httpwriter: unexpected status %d: %s", e.Status, e.Body)
+}
+
+// RetryPolicy configures the exponential backoff used to retry a failed
+// write. The delay before the n-th retry is InitialInterval * Multiplier^n,
+// capped at MaxInterval, unless the response carried a Retry-After header,
+// which takes precedence. Retrying stops once MaxElapsedTime has passed
+// since the first attempt, or once Classify says the error isn't worth
+// retrying.
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	Multiplier      float64
+	MaxInterval     time.Duration
+	// MaxElapsedTime bounds how long a write keeps retrying; zero means it
+	// retries until Context is done.
+	MaxElapsedTime time.Duration
+	// Classify decides whether err is worth retrying, nil retries every error.
+	Classify func(err error) bool
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	max := p.MaxInterval
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	d := time.Duration(float64(initial) * math.Pow(mult, float64(attempt)))
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d
+}
+
+func (p *RetryPolicy) shouldRetry(err error, elapsed time.Duration) bool {
+	if p.MaxElapsedTime > 0 && elapsed > p.MaxElapsedTime {
+		return false
+	}
+	if p.Classify != nil && !p.Classify(err) {
+		return false
+	}
+	return true
+}
+
+// requestState pools a *http.Request together with the bytes.Reader backing
+// its body, to avoid allocating both on every write.
+type requestState struct {
+	req    *http.Request
+	reader *bytes.Reader
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(ioutil.Discard)
+	},
+}
+
+// Write sends p to the endpoint, retrying on failure per RetryPolicy.
+func (w *Writer) Write(p []byte) (int, error) {
+	ctx := w.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	header := w.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	body := p
+	if header.Get("Content-Encoding") == "gzip" {
+		gzipped, err := gzipBody(p)
+		if err != nil {
+			return 0, err
+		}
+		body = gzipped
+	}
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := w.do(ctx, header, body)
+		if err == nil {
+			return len(p), nil
+		}
+
+		if w.RetryPolicy == nil || !w.RetryPolicy.shouldRetry(err, time.Since(start)) {
+			return 0, err
+		}
+
+		delay := w.RetryPolicy.backoff(attempt)
+		if herr, ok := err.(*HTTPError); ok {
+			if ra := retryAfter(herr.Header); ra > 0 {
+				delay = ra
+			}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// do builds and sends a single request for body, reusing a pooled
+// *http.Request and bytes.Reader private to this Writer. The cached request
+// is only reused while it still targets w.URL, so two Writers (or the same
+// Writer after its URL changes) never cross-send to each other's endpoint.
+func (w *Writer) do(ctx context.Context, header http.Header, body []byte) error {
+	st, _ := w.reqPool.Get().(*requestState)
+	if st == nil {
+		st = &requestState{reader: new(bytes.Reader)}
+	}
+	defer w.reqPool.Put(st)
+
+	st.reader.Reset(body)
+	if st.req == nil || st.req.URL == nil || st.req.URL.String() != w.URL {
+		req, err := http.NewRequest(w.Method, w.URL, st.reader)
+		if err != nil {
+			return err
+		}
+		st.req = req
+	}
+
+	req := st.req.WithContext(ctx)
+	req.Method = w.Method
+	req.Header = header
+	req.ContentLength = int64(len(body))
+	req.Body = ioutil.NopCloser(st.reader)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &HTTPError{Status: res.StatusCode, Body: respBody, Header: res.Header}
+	}
+	return nil
+}
+
+// gzipBody compresses p using a pooled gzip.Writer.
+func gzipBody(p []byte) ([]byte, error) {
+	gw := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(gw)
+
+	var buf bytes.Buffer
+	gw.Reset(&buf)
+	if _, err := gw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// retryAfter parses a Retry-After header, either a number of seconds or an
+// HTTP-date, returning 0 if absent or unparsable.
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package httpwriter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWriter_PerInstancePool ensures two Writers pointed at different URLs
+// never cross-send to each other's endpoint through a shared pooled request.
+func TestWriter_PerInstancePool(t *testing.T) {
+	var gotA, gotB []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endpoint-a":
+			gotA = append(gotA, r.URL.Path)
+		case "/endpoint-b":
+			gotB = append(gotB, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := &Writer{Client: srv.Client(), Method: http.MethodPost, URL: srv.URL + "/endpoint-a"}
+	b := &Writer{Client: srv.Client(), Method: http.MethodPost, URL: srv.URL + "/endpoint-b"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := a.Write([]byte("a")); err != nil {
+			t.Fatalf("a.Write: %v", err)
+		}
+		if _, err := b.Write([]byte("b")); err != nil {
+			t.Fatalf("b.Write: %v", err)
+		}
+	}
+
+	if len(gotA) != 3 {
+		t.Fatalf("endpoint-a got %d requests, want 3", len(gotA))
+	}
+	if len(gotB) != 3 {
+		t.Fatalf("endpoint-b got %d requests, want 3 (leaked to endpoint-a?)", len(gotB))
+	}
+}
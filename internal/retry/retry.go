@@ -0,0 +1,57 @@
+// Package retry holds the shared RetryPolicy implementation behind
+// syncio.RetryPolicy and asyncio.RetryPolicy, which used to carry
+// byte-identical copies of this logic.
+package retry
+
+import (
+	"math"
+	"time"
+)
+
+// RetryPolicy configures the exponential backoff used to retry a failed
+// flush. The delay before the n-th retry is InitialInterval * Multiplier^n,
+// capped at MaxInterval. Retrying stops once MaxElapsedTime has passed since
+// the first attempt, or once Classify says the error isn't worth retrying.
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	Multiplier      float64
+	MaxInterval     time.Duration
+	// MaxElapsedTime bounds how long a flush keeps retrying; zero means
+	// it retries until the caller's shutdown deadline expires.
+	MaxElapsedTime time.Duration
+	// Classify decides whether err is worth retrying, nil retries every error.
+	Classify func(err error) bool
+}
+
+// Backoff returns the delay to wait before the given retry attempt (0-based).
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	max := p.MaxInterval
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	d := time.Duration(float64(initial) * math.Pow(mult, float64(attempt)))
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d
+}
+
+// ShouldRetry reports whether a flush that failed with err, elapsed since
+// the first attempt, is still worth retrying.
+func (p *RetryPolicy) ShouldRetry(err error, elapsed time.Duration) bool {
+	if p.MaxElapsedTime > 0 && elapsed > p.MaxElapsedTime {
+		return false
+	}
+	if p.Classify != nil && !p.Classify(err) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,317 @@
+package elastic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// bulkAction is the first line of a bulk item, naming the operation
+// (index, create, update or delete) the following source line applies to.
+type bulkAction struct {
+	Index  *bulkActionMeta `json:"index,omitempty"`
+	Create *bulkActionMeta `json:"create,omitempty"`
+	Update *bulkActionMeta `json:"update,omitempty"`
+	Delete *bulkActionMeta `json:"delete,omitempty"`
+}
+
+type bulkActionMeta struct {
+	Index string `json:"_index,omitempty"`
+	Type  string `json:"_type,omitempty"`
+	ID    string `json:"_id,omitempty"`
+}
+
+// bulkItem is a pair of NDJSON lines as sent to the _bulk endpoint: the
+// action line and, unless the action is delete, the source line.
+type bulkItem struct {
+	action []byte
+	source []byte
+	// attempts counts how many times this item has been sent
+	attempts int
+}
+
+func (i bulkItem) bytes() []byte {
+	if i.source == nil {
+		return append(append([]byte{}, i.action...), '\n')
+	}
+	b := make([]byte, 0, len(i.action)+len(i.source)+2)
+	b = append(b, i.action...)
+	b = append(b, '\n')
+	b = append(b, i.source...)
+	b = append(b, '\n')
+	return b
+}
+
+type bulkResponse struct {
+	Took   int                      `json:"took"`
+	Errors bool                     `json:"errors"`
+	Items  []map[string]bulkItemRes `json:"items"`
+}
+
+type bulkItemRes struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// BulkWriter writes Elasticsearch `_bulk` request bodies to an http.Client,
+// understanding the bulk response envelope: it inspects the status of each
+// item, retries the ones rejected because the cluster is overloaded and
+// drops the ones that failed because of malformed documents, reporting the
+// latter through OnItemError.
+type BulkWriter struct {
+	Client *http.Client
+	URL    string
+	Header http.Header
+
+	// MaxRetries bounds how many times a rejected item is resent before
+	// it is given up and handed to OnItemError. Defaults to 5.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry. Defaults to 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff between retries. Defaults to 30s.
+	MaxBackoff time.Duration
+	// GzipThreshold gzips the request body when its size in bytes is
+	// greater than this value. 0 disables compression.
+	GzipThreshold int
+
+	// OnItemError is called for every item that is dropped: either
+	// because it was rejected with a non-retryable status or because it
+	// exhausted MaxRetries. reason is the Elasticsearch error type, or
+	// the give-up cause when retries are exhausted.
+	OnItemError func(doc []byte, reason string)
+
+	stats Stats
+}
+
+// Stats contains counters describing what happened to the documents
+// written through a BulkWriter.
+type Stats struct {
+	// Accepted is the number of items indexed successfully.
+	Accepted int32
+	// Rejected is the number of items that failed with a retryable status.
+	Rejected int32
+	// Retried is the number of times an item was resent after rejection.
+	Retried int32
+	// Dropped is the number of items given up on and sent to OnItemError.
+	Dropped int32
+}
+
+// Stats returns a copy of the current writer stats.
+func (w *BulkWriter) Stats() Stats {
+	return w.stats
+}
+
+const maxRetriesDefault = 5
+const initialBackoffDefault = 500 * time.Millisecond
+const maxBackoffDefault = 30 * time.Second
+
+// Write sends p, a complete `_bulk` request body, and retries the items
+// rejected with a 429 (es_rejected_execution_exception) or 503 using
+// exponential backoff with jitter, up to MaxRetries. It always reports
+// len(p), nil unless the request itself couldn't be built or sent, so that
+// callers don't retry the whole body on a partial, item-level failure.
+func (w *BulkWriter) Write(p []byte) (int, error) {
+	items, err := splitBulkBody(p)
+	if err != nil {
+		return 0, err
+	}
+
+	maxRetries := w.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = maxRetriesDefault
+	}
+	initialBackoff := w.InitialBackoff
+	if initialBackoff == 0 {
+		initialBackoff = initialBackoffDefault
+	}
+	maxBackoff := w.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = maxBackoffDefault
+	}
+
+	pending := items
+	for attempt := 0; len(pending) > 0; attempt++ {
+		retry, err := w.sendBulk(pending)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(retry) == 0 {
+			break
+		}
+		if attempt >= maxRetries {
+			for _, it := range retry {
+				atomic.AddInt32(&w.stats.Dropped, 1)
+				if w.OnItemError != nil {
+					w.OnItemError(it.source, "max retries exceeded")
+				}
+			}
+			break
+		}
+
+		atomic.AddInt32(&w.stats.Retried, int32(len(retry)))
+		time.Sleep(backoff(attempt, initialBackoff, maxBackoff))
+		pending = retry
+	}
+
+	return len(p), nil
+}
+
+// sendBulk sends the given items as a single bulk request and returns the
+// items that should be retried because of a transient failure.
+func (w *BulkWriter) sendBulk(items []bulkItem) ([]bulkItem, error) {
+	var body bytes.Buffer
+	for _, it := range items {
+		body.Write(it.bytes())
+	}
+
+	header := w.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if w.GzipThreshold > 0 && body.Len() > w.GzipThreshold {
+		var gzipped bytes.Buffer
+		gw := gzip.NewWriter(&gzipped)
+		if _, err := gw.Write(body.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+		body = gzipped
+		header.Set("Content-Encoding", "gzip")
+	}
+	header.Set("Content-Type", "application/x-ndjson")
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("X-Elastic-Product") != "Elasticsearch" {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil, &HTTPError{Status: res.StatusCode, Body: []byte("missing X-Elastic-Product header: not an Elasticsearch endpoint")}
+	}
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		return nil, &HTTPError{Status: res.StatusCode, Body: respBody}
+	}
+
+	var br bulkResponse
+	if err := json.Unmarshal(respBody, &br); err != nil {
+		return nil, err
+	}
+	if !br.Errors {
+		atomic.AddInt32(&w.stats.Accepted, int32(len(items)))
+		return nil, nil
+	}
+	if len(br.Items) != len(items) {
+		return nil, &HTTPError{Status: res.StatusCode, Body: respBody}
+	}
+
+	var retry []bulkItem
+	for i, result := range br.Items {
+		var res bulkItemRes
+		for _, r := range result {
+			res = r
+			break
+		}
+
+		if res.Status < 300 {
+			atomic.AddInt32(&w.stats.Accepted, 1)
+			continue
+		}
+
+		errType := ""
+		if res.Error != nil {
+			errType = res.Error.Type
+		}
+
+		if res.Status == http.StatusTooManyRequests || res.Status == http.StatusServiceUnavailable {
+			atomic.AddInt32(&w.stats.Rejected, 1)
+			items[i].attempts++
+			retry = append(retry, items[i])
+			continue
+		}
+
+		atomic.AddInt32(&w.stats.Dropped, 1)
+		if w.OnItemError != nil {
+			w.OnItemError(items[i].source, errType)
+		}
+	}
+
+	return retry, nil
+}
+
+// splitBulkBody parses a `_bulk` request body into its individual items,
+// pairing every action line with its source line except for delete actions,
+// which carry no source.
+func splitBulkBody(p []byte) ([]bulkItem, error) {
+	lines := bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n"))
+
+	var items []bulkItem
+	for i := 0; i < len(lines); i++ {
+		if len(lines[i]) == 0 {
+			continue
+		}
+		var a bulkAction
+		if err := json.Unmarshal(lines[i], &a); err != nil {
+			return nil, err
+		}
+
+		it := bulkItem{action: lines[i]}
+		if a.Delete == nil {
+			i++
+			if i >= len(lines) {
+				return nil, &HTTPError{Body: []byte("bulk body ended without a source line")}
+			}
+			it.source = lines[i]
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// backoff returns the delay before the given retry attempt (0-indexed),
+// growing exponentially from initial up to max, plus up to 20% jitter.
+func backoff(attempt int, initial, max time.Duration) time.Duration {
+	d := initial << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/5 + 1))
+	return d + jitter
+}
+
+// HTTPError is returned when Elasticsearch responds with a status that
+// can't be resolved at the item level (transport-level rejection, an
+// unparsable bulk response, or a non-Elasticsearch endpoint).
+type HTTPError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *HTTPError) Error() string {
+	return "elastic bulk request failed, status " + http.StatusText(e.Status) + ": " + string(e.Body)
+}
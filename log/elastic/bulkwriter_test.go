@@ -0,0 +1,97 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type bulkItemRespBody struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// TestBulkWriter_RetriesRejectedItems checks that an item rejected with a
+// 429 is retried until it's accepted, while a sibling item that fails with a
+// non-retryable status is dropped to OnItemError instead.
+func TestBulkWriter_RetriesRejectedItems(t *testing.T) {
+	var calls int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		n := atomic.AddInt32(&calls, 1)
+
+		reqBody, _ := ioutil.ReadAll(r.Body)
+		lines := bytes.Split(bytes.TrimRight(reqBody, "\n"), []byte("\n"))
+		nItems := len(lines) / 2
+
+		items := make([]map[string]bulkItemRespBody, 0, nItems)
+		for i := 0; i < nItems; i++ {
+			var res bulkItemRespBody
+			if bytes.Contains(lines[i*2], []byte(`"_id":"1"`)) {
+				if n == 1 {
+					res.Status = http.StatusTooManyRequests
+				} else {
+					res.Status = http.StatusOK
+				}
+			} else {
+				res.Status = http.StatusBadRequest
+				res.Error = &struct {
+					Type string `json:"type"`
+				}{Type: "mapper_parsing_exception"}
+			}
+			items = append(items, map[string]bulkItemRespBody{"index": res})
+		}
+
+		resp := struct {
+			Errors bool                          `json:"errors"`
+			Items  []map[string]bulkItemRespBody `json:"items"`
+		}{Errors: true, Items: items}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var dropErrs []string
+	w := &BulkWriter{
+		Client:         srv.Client(),
+		URL:            srv.URL,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		OnItemError: func(doc []byte, reason string) {
+			dropErrs = append(dropErrs, reason)
+		},
+	}
+
+	body := []byte(
+		`{"index":{"_id":"1"}}` + "\n" + `{"field":"retry-me"}` + "\n" +
+			`{"index":{"_id":"2"}}` + "\n" + `{"field":"bad-doc"}` + "\n")
+
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("server called %d times, want 2 (one retry)", got)
+	}
+	if len(dropErrs) != 1 || dropErrs[0] != "mapper_parsing_exception" {
+		t.Fatalf("OnItemError = %v, want one mapper_parsing_exception", dropErrs)
+	}
+
+	stats := w.Stats()
+	if stats.Accepted != 1 {
+		t.Errorf("Accepted = %d, want 1", stats.Accepted)
+	}
+	if stats.Rejected != 1 {
+		t.Errorf("Rejected = %d, want 1", stats.Rejected)
+	}
+	if stats.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", stats.Dropped)
+	}
+}
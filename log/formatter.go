@@ -2,15 +2,17 @@ package log
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
 )
 
 type Formatter interface {
-	Format(*bytes.Buffer, string, Level)
+	Format(buf *bytes.Buffer, msg string, lvl Level, fields Fields)
 }
 
 type TextFormatter struct{}
 
-func (TextFormatter) Format(buf *bytes.Buffer, m string, lvl Level) {
+func (TextFormatter) Format(buf *bytes.Buffer, m string, lvl Level, fields Fields) {
 	const (
 		red    = "\x1b[31;1m"
 		yellow = "\x1b[33;1m"
@@ -35,5 +37,29 @@ func (TextFormatter) Format(buf *bytes.Buffer, m string, lvl Level) {
 	case LvlVerbose:
 		prefix = cyan + "VERBOSE " + reset
 	}
-	buf.WriteString(prefix + m + "\n")
+	buf.WriteString(prefix + m)
+	for k, v := range fields {
+		s := fmt.Sprintf("%v", v)
+		if needsQuote(s) {
+			s = strconv.Quote(s)
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(s)
+	}
+	buf.WriteByte('\n')
+}
+
+// needsQuote reports whether s must be quoted to keep a field on a single
+// line of output: a space would otherwise run into the surrounding text,
+// and a control character such as \n or \r would split one log entry across
+// multiple physical lines.
+func needsQuote(s string) bool {
+	for _, r := range s {
+		if r == ' ' || r < 0x20 {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,35 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestTextFormatter_QuotesControlCharacters checks that a field value
+// containing a newline is quoted, so the entry stays on a single physical
+// line instead of splitting into several, which would desync a line-oriented
+// consumer such as tail -f.
+func TestTextFormatter_QuotesControlCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	TextFormatter{}.Format(&buf, "msg", LvlInfo, Fields{"stack": "line1\nline2"})
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d physical lines, want 1: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `stack="line1\nline2"`) {
+		t.Fatalf("line = %q, want a quoted stack field", lines[0])
+	}
+}
+
+// TestTextFormatter_DoesNotQuotePlainFields checks that a field not
+// containing whitespace or control characters is left unquoted.
+func TestTextFormatter_DoesNotQuotePlainFields(t *testing.T) {
+	var buf bytes.Buffer
+	TextFormatter{}.Format(&buf, "msg", LvlInfo, Fields{"count": 3})
+
+	if !strings.Contains(buf.String(), "count=3") {
+		t.Fatalf("got %q, want unquoted count=3", buf.String())
+	}
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONFormatter renders one NDJSON object per log line, with "@timestamp",
+// "level", "message" and "caller" plus every field merged at the top level.
+// It reuses the package-level bufferPool through Logger.Log like
+// TextFormatter, and avoids encoding/json on the hot path by writing
+// primitives directly.
+type JSONFormatter struct{}
+
+// logPkg is the import path of this package, used by callerFrame to skip
+// past its own frames (Log, Logf, and the package-level Info/Infof/...
+// wrappers) regardless of how many of them sit between the user's call site
+// and Format; those call chains aren't the same depth, so a fixed skip count
+// reports the wrong line for one path or the other.
+const logPkg = "github.com/travelgateX/go-io/log."
+
+// callerFrame returns the file:line of the first stack frame outside this
+// package, i.e. the user's actual call site.
+func callerFrame() (file string, line int, ok bool) {
+	var pcs [16]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, logPkg) {
+			return frame.File, frame.Line, true
+		}
+		if !more {
+			return "", 0, false
+		}
+	}
+}
+
+func (JSONFormatter) Format(buf *bytes.Buffer, m string, lvl Level, fields Fields) {
+	buf.WriteByte('{')
+
+	buf.WriteString(`"@timestamp":`)
+	writeJSONString(buf, time.Now().UTC().Format(time.RFC3339Nano))
+
+	buf.WriteString(`,"level":`)
+	writeJSONString(buf, lvl.String())
+
+	buf.WriteString(`,"message":`)
+	writeJSONString(buf, m)
+
+	if file, line, ok := callerFrame(); ok {
+		buf.WriteString(`,"caller":`)
+		writeJSONString(buf, file+":"+strconv.Itoa(line))
+	}
+
+	for k, v := range fields {
+		buf.WriteByte(',')
+		writeJSONString(buf, k)
+		buf.WriteByte(':')
+		writeJSONValue(buf, v)
+	}
+
+	buf.WriteString("}\n")
+}
+
+// writeJSONValue writes v as a JSON value, handling the field types common
+// in log.Fields without going through encoding/json.
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case string:
+		writeJSONString(buf, val)
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case int:
+		buf.WriteString(strconv.Itoa(val))
+	case int64:
+		buf.WriteString(strconv.FormatInt(val, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+	default:
+		writeJSONString(buf, fmt.Sprintf("%v", val))
+	}
+}
+
+// writeJSONString writes s as a quoted JSON string, escaping the characters
+// encoding/json would: quote, backslash and control characters.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				const hex = "0123456789abcdef"
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xf])
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,53 @@
+// Package log_test exercises JSONFormatter's caller reporting from outside
+// package log, since callerFrame walks up the stack until it leaves the
+// log package itself.
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/travelgateX/go-io/log"
+)
+
+// TestJSONFormatter_Caller checks that the reported caller is the test's own
+// call site, both through the plain Log path and the Logf path, which sit at
+// different stack depths.
+func TestJSONFormatter_Caller(t *testing.T) {
+	var buf bytes.Buffer
+	l := &log.Logger{
+		Setups: []log.Setup{{F: log.JSONFormatter{}, W: &buf}},
+		MaxLvl: log.LvlVerbose,
+	}
+
+	_, wantFile, wantLogLine, _ := runtime.Caller(0)
+	l.Log(log.LvlInfo, "plain")
+	wantLogLine++ // l.Log is called on the next source line
+
+	_, _, wantLogfLine, _ := runtime.Caller(0)
+	l.Logf(log.LvlInfo, "formatted %d", 1)
+	wantLogfLine++
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %q", len(lines), buf.String())
+	}
+
+	checkCaller := func(line string, wantLine int) {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("unmarshal %q: %v", line, err)
+		}
+		caller, _ := m["caller"].(string)
+		want := wantFile + ":" + strconv.Itoa(wantLine)
+		if caller != want {
+			t.Errorf("caller = %q, want %q", caller, want)
+		}
+	}
+	checkCaller(lines[0], wantLogLine)
+	checkCaller(lines[1], wantLogfLine)
+}
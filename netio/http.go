@@ -1,35 +1,18 @@
 package netio
 
-import (
-	"bytes"
-	"io"
-	"io/ioutil"
-	"net/http"
-)
+import "github.com/travelgateX/go-io/internal/httpwriter"
 
 // HTTPWriter gives io.Write methods to a http Client
-type HTTPWriter struct {
-	Client *http.Client
-	Method string
-	URL    string
-	Header http.Header
-}
+type HTTPWriter = httpwriter.Writer
 
-func (w *HTTPWriter) Write(p []byte) (int, error) {
-	br := bytes.NewReader(p)
-	req, err := http.NewRequest(w.Method, w.URL, br)
-	if err != nil {
-		return 0, err
-	}
+// HTTPError is returned when the endpoint responds with a non-2xx status,
+// so that buffered writers see it as a failed write and can retry it.
+type HTTPError = httpwriter.HTTPError
 
-	req.Header = w.Header
-
-	res, err := w.Client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
-	return len(p), nil
-}
+// RetryPolicy configures the exponential backoff used to retry a failed
+// write. The delay before the n-th retry is InitialInterval * Multiplier^n,
+// capped at MaxInterval, unless the response carried a Retry-After header,
+// which takes precedence. Retrying stops once MaxElapsedTime has passed
+// since the first attempt, or once Classify says the error isn't worth
+// retrying.
+type RetryPolicy = httpwriter.RetryPolicy
@@ -1,17 +1,28 @@
 package syncio
 
 import (
+	"context"
 	"errors"
 	"io"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/travelgateX/go-io/syncio/internal"
+	"github.com/travelgateX/go-io/bufpool"
+	"github.com/travelgateX/go-io/internal/retry"
 )
 
 var _ io.WriteCloser = &Buffer{}
 
+// Writev is implemented by writers that can coalesce several buffers into a
+// single underlying write, e.g. a writer backed by a writev(2) syscall.
+// When the writer passed to NewBuffer implements it, batched flushes are
+// handed to it directly instead of going through net.Buffers.
+type Writev interface {
+	WritevBuffers(net.Buffers) (int64, error)
+}
+
 // Buffer is a buffer which implements io.WriteCloser methods where writes can be done
 // concurrently, the writes store the data in a buffer thats its later flushed to an underlying writer
 // when its full or ticks.
@@ -19,22 +30,46 @@ var _ io.WriteCloser = &Buffer{}
 // Closing blocks the caller until all writes finish.
 type Buffer struct {
 	bufmu sync.Mutex
-	buf   *internal.Buffer
+	buf   *bufpool.Buffer
 	// buffer write operations to the underlaying writer are potentially slow, a new buffer
 	// takes the stage when the current is sent to write
-	pool *internal.BufferPool
+	pool bufpool.Pool
 
 	writer        io.Writer
 	bufSize       int
 	poolSize      int
 	flushInterval time.Duration
 
+	// maxBatchedBuffers bounds how many filled buffers a single flush
+	// will coalesce into one underlying write
+	maxBatchedBuffers int
+	// flushQueue is an unbounded FIFO of filled buffers waiting to be
+	// written; a single goroutine drains it so bytes reach the underlying
+	// writer in the order they were flushed. It's unbounded, and guarded
+	// by its own mutex rather than bufmu, so that a retry sleeping on
+	// backoff in that goroutine never blocks flush() - which always runs
+	// with bufmu held - regardless of how long the retry takes.
+	flushQueue *flushQueue
+	// flushDone is closed once the flush goroutine drains flushQueue
+	// after it's been closed
+	flushDone chan struct{}
+
+	// retryPolicy governs how a failed flush is retried before giving up,
+	// nil means a failed flush is never retried
+	retryPolicy *RetryPolicy
+	// onError is invoked once a flush gives up retrying
+	onError func(err error, payload []byte, attempt int)
+	// shutdownCtx bounds how long in-flight retries are allowed to keep
+	// sleeping once CloseContext is called; read from the flush goroutine
+	shutdownCtx atomic.Value
+
 	// control flag to not flush per tick if a flush is
 	// already done by full buffer
 	flushedBetweenTicks bool
 
 	closed bool
-	// wg is a group of writes in progress
+	// wg is a group of the oversized writes in progress, which bypass
+	// the buffer pool and are written directly to the underlying writer
 	wg sync.WaitGroup
 
 	stats Stats
@@ -60,25 +95,39 @@ func NewBuffer(w io.Writer, options ...BufferOption) *Buffer {
 	if tb.poolSize == 0 {
 		tb.poolSize = defaultPoolSize
 	}
-	tb.pool = internal.NewBufferPool(tb.poolSize, tb.bufSize)
+	if tb.maxBatchedBuffers == 0 {
+		tb.maxBatchedBuffers = tb.poolSize
+	}
+	if tb.pool == nil {
+		tb.pool = bufpool.NewChannelPool(tb.poolSize, tb.bufSize)
+	}
 	tb.buf = tb.getBuffer()
 
+	tb.flushQueue = newFlushQueue()
+	tb.flushDone = make(chan struct{})
+	tb.shutdownCtx.Store(context.Background())
+	go tb.runFlusher()
+
 	if tb.flushInterval > 0 {
 		go func() {
 			t := time.NewTicker(tb.flushInterval)
-			for !tb.closed {
-				select {
-				case <-t.C:
-					tb.bufmu.Lock()
-					if !tb.flushedBetweenTicks {
-						tb.flush()
-					} else {
-						tb.flushedBetweenTicks = false
-					}
+			defer t.Stop()
+			for {
+				<-t.C
+				tb.bufmu.Lock()
+				// re-check closed under the lock: CloseContext may have
+				// run between the tick firing and us acquiring the lock.
+				if tb.closed {
 					tb.bufmu.Unlock()
+					return
+				}
+				if !tb.flushedBetweenTicks {
+					tb.flush()
+				} else {
+					tb.flushedBetweenTicks = false
 				}
+				tb.bufmu.Unlock()
 			}
-			t.Stop()
 		}()
 	}
 
@@ -111,6 +160,47 @@ func SetFlushInterval(d time.Duration) BufferOption {
 	}
 }
 
+// SetBufferPool swaps the default channel-backed buffer pool for a custom
+// implementation, e.g. to share one pool across many Buffer instances, or
+// bufpool.NopPool{} to measure pooling overhead against plain allocation.
+func SetBufferPool(p bufpool.Pool) BufferOption {
+	return func(b *Buffer) {
+		b.pool = p
+	}
+}
+
+// SetMaxBatchedBuffers sets the maximum number of filled buffers that a single
+// flush will coalesce into one underlying write. Defaults to the pool size.
+func SetMaxBatchedBuffers(n int) BufferOption {
+	return func(b *Buffer) {
+		b.maxBatchedBuffers = n
+	}
+}
+
+// SetRetryPolicy makes a failed flush retry with the given backoff instead
+// of being given up on after a single attempt. The buffer being flushed is
+// held, not returned to the pool, until the retry succeeds or is given up.
+func SetRetryPolicy(p RetryPolicy) BufferOption {
+	return func(b *Buffer) {
+		b.retryPolicy = &p
+	}
+}
+
+// SetOnError registers a callback invoked when a flush gives up retrying,
+// with the payload that couldn't be written, so callers can persist it or
+// alert instead of losing it silently.
+func SetOnError(f func(err error, payload []byte, attempt int)) BufferOption {
+	return func(b *Buffer) {
+		b.onError = f
+	}
+}
+
+// RetryPolicy configures the exponential backoff used to retry a failed
+// flush. The delay before the n-th retry is InitialInterval * Multiplier^n,
+// capped at MaxInterval. Retrying stops once MaxElapsedTime has passed since
+// the first attempt, or once Classify says the error isn't worth retrying.
+type RetryPolicy = retry.RetryPolicy
+
 // ErrWriteOnClosed is returned when a write is done after closing
 var ErrWriteOnClosed = errors.New("write on closed writer")
 
@@ -133,13 +223,7 @@ func (tb *Buffer) Write(p []byte) (int, error) {
 		b := make([]byte, lenP)
 		copy(b, p)
 		tb.wg.Add(1)
-		go func() {
-			_, err := tb.writer.Write(b)
-			if err != nil {
-				atomic.AddInt32(&tb.stats.FlushErrors, 1)
-			}
-			tb.wg.Done()
-		}()
+		go tb.writeDirect(b)
 		return lenP, nil
 	}
 
@@ -154,40 +238,261 @@ func (tb *Buffer) Write(p []byte) (int, error) {
 
 // Close is concurrent safe and blocks until the remaining data in buffer is flushed
 func (tb *Buffer) Close() error {
+	return tb.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but only waits for in-flight retries until ctx
+// is done; buffers still being retried past that deadline are handed to
+// OnError instead of being lost to a goroutine that never stops retrying.
+func (tb *Buffer) CloseContext(ctx context.Context) error {
+	tb.shutdownCtx.Store(ctx)
+
+	// Setting closed and flushing the residual buffer happen under bufmu,
+	// so they're atomic with respect to the ticker goroutine and
+	// concurrent Write calls, which also flush under bufmu.
 	tb.bufmu.Lock()
 	tb.closed = true
-	tb.bufmu.Unlock()
-
-	// flush remaining data in buffers
 	tb.flush()
+	tb.bufmu.Unlock()
 
+	tb.flushQueue.close()
+	<-tb.flushDone
 	tb.wg.Wait()
 	return nil
 }
 
-// flush writes all the data of the current buffer to the underlying writer
-// the buffer used to write is put in background and its sent back to the
-// buffer pool when its operation finishes. A new buffer is obtained to continue
-// serving incoming writes.
+// flush hands the current buffer to the flush queue and takes a fresh one
+// from the pool to keep serving incoming writes. The actual write happens
+// in the background, in the single goroutine started by runFlusher, which
+// preserves the ordering of the bytes reaching the underlying writer.
+// flushQueue is unbounded, so this never blocks, even while runFlusher is
+// stuck sleeping on a retry's backoff - flush() always runs with bufmu
+// held, and a blocking send here would stall every concurrent Write.
 func (tb *Buffer) flush() {
 	if tb.buf.Buffered() == 0 {
 		return
 	}
 	buf := tb.buf
 	tb.buf = tb.getBuffer()
-	tb.wg.Add(1)
-	go func() {
-		_, err := buf.WriteTo(tb.writer)
-		if err != nil {
-			atomic.AddInt32(&tb.stats.FlushErrors, 1)
+	tb.flushQueue.push(buf)
+}
+
+// runFlusher drains flushQueue until it's closed and drained, coalescing
+// whatever buffers are already queued (up to maxBatchedBuffers) into a
+// single underlying write. It's the only goroutine allowed to write to
+// tb.writer, so buffers reach it in the order they were flushed.
+func (tb *Buffer) runFlusher() {
+	defer close(tb.flushDone)
+	for {
+		batch, closed := tb.flushQueue.drain(tb.maxBatchedBuffers)
+		if len(batch) == 0 {
+			if closed {
+				return
+			}
+			tb.flushQueue.wait()
+			continue
 		}
+		tb.writeBatch(batch)
+	}
+}
+
+// flushQueue is an unbounded FIFO of buffers waiting to be written, guarded
+// by its own mutex instead of the Buffer's bufmu. Decoupling it from bufmu
+// means push, called from flush() with bufmu held, never blocks regardless
+// of how long the draining goroutine is stuck retrying a write.
+type flushQueue struct {
+	mu     sync.Mutex
+	items  []*bufpool.Buffer
+	closed bool
+	// wake is buffered(1) and signals the drainer that items or closed
+	// changed; a full channel means a signal is already pending, so the
+	// send is dropped instead of blocking.
+	wake chan struct{}
+}
+
+func newFlushQueue() *flushQueue {
+	return &flushQueue{wake: make(chan struct{}, 1)}
+}
+
+// push appends buf to the queue and wakes the drainer. It never blocks.
+func (q *flushQueue) push(buf *bufpool.Buffer) {
+	q.mu.Lock()
+	q.items = append(q.items, buf)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// close marks the queue as closed: once drained empty, drain reports it as
+// such so runFlusher can stop. It never blocks.
+func (q *flushQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *flushQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until push or close has something new for drain to see.
+func (q *flushQueue) wait() {
+	<-q.wake
+}
+
+// drain returns every buffer currently queued, up to max, and whether the
+// queue is closed and now empty. It never blocks.
+func (q *flushQueue) drain(max int) (batch []*bufpool.Buffer, closed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.items)
+	if n > max {
+		n = max
+	}
+	batch = append(batch, q.items[:n]...)
+	q.items = q.items[n:]
+	return batch, q.closed && len(q.items) == 0
+}
+
+// writeBatch writes the batch to the underlying writer, using a single
+// writev(2)-backed call when possible. On failure, and if a RetryPolicy was
+// set, it keeps retrying with backoff instead of dropping the batch: the
+// buffers are only returned to the pool once the write succeeds or is given
+// up on, either because the policy says so or because CloseContext's
+// deadline has passed. A retry only resends the part of the batch that a
+// prior attempt didn't already confirm written, since a multi-buffer write
+// can fail partway through and still have delivered some of it to the sink.
+func (tb *Buffer) writeBatch(batch []*bufpool.Buffer) {
+	if len(batch) > 1 {
+		atomic.AddInt32(&tb.stats.BatchedFlushes, 1)
+	}
+
+	var sent int64
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		unsent := unsentBuffers(batch, sent)
+		n, err := tb.doWrite(unsent)
+		sent += n
+		atomic.AddInt64(&tb.stats.VectorBytes, n)
+		if err == nil {
+			break
+		}
+		atomic.AddInt32(&tb.stats.FlushErrors, 1)
+
+		if tb.retryPolicy == nil || !tb.retryPolicy.ShouldRetry(err, time.Since(start)) {
+			tb.giveUp(batch, unsentBuffers(batch, sent), err, attempt)
+			return
+		}
+
+		ctx := tb.shutdownCtx.Load().(context.Context)
+		select {
+		case <-time.After(tb.retryPolicy.Backoff(attempt)):
+		case <-ctx.Done():
+			tb.giveUp(batch, unsentBuffers(batch, sent), err, attempt)
+			return
+		}
+	}
+
+	for _, buf := range batch {
 		tb.pool.Put(buf)
-		tb.wg.Done()
-	}()
+	}
+}
+
+// unsentBuffers returns the net.Buffers view of batch that's left after sent
+// bytes of it have already reached the underlying writer: buffers it fully
+// covers are skipped, and the buffer it ends in is trimmed to its remainder.
+func unsentBuffers(batch []*bufpool.Buffer, sent int64) net.Buffers {
+	bufs := make(net.Buffers, 0, len(batch))
+	for _, buf := range batch {
+		b := buf.Bytes()
+		if int64(len(b)) <= sent {
+			sent -= int64(len(b))
+			continue
+		}
+		bufs = append(bufs, b[sent:])
+		sent = 0
+	}
+	return bufs
+}
+
+// doWrite performs the actual write of bufs to the underlying writer.
+func (tb *Buffer) doWrite(bufs net.Buffers) (int64, error) {
+	if len(bufs) == 0 {
+		return 0, nil
+	}
+	if len(bufs) == 1 {
+		n, err := tb.writer.Write(bufs[0])
+		return int64(n), err
+	}
+
+	if wv, ok := tb.writer.(Writev); ok {
+		return wv.WritevBuffers(bufs)
+	}
+	// net.Buffers.WriteTo issues a single writev(2) when the writer
+	// supports it (e.g. *net.TCPConn), and falls back to sequential
+	// Write calls otherwise.
+	return bufs.WriteTo(tb.writer)
+}
+
+// giveUp reports the still-unsent part of the batch to OnError, if set, and
+// returns every buffer in the batch to the pool; the caller is done
+// retrying them.
+func (tb *Buffer) giveUp(batch []*bufpool.Buffer, unsent net.Buffers, err error, attempt int) {
+	if tb.onError != nil {
+		for _, b := range unsent {
+			tb.onError(err, append([]byte(nil), b...), attempt)
+		}
+	}
+	for _, buf := range batch {
+		tb.pool.Put(buf)
+	}
+}
+
+// writeDirect writes p straight to the underlying writer, bypassing the
+// buffer pool, for writes too big to fit a single buffer. On failure it
+// retries with backoff the same way writeBatch does, only resending the
+// part of p not yet confirmed written, and reports whatever's left to
+// OnError once it gives up instead of dropping it silently.
+func (tb *Buffer) writeDirect(p []byte) {
+	defer tb.wg.Done()
+
+	var sent int
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		n, err := tb.writer.Write(p[sent:])
+		sent += n
+		if err == nil && sent != len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			return
+		}
+		atomic.AddInt32(&tb.stats.FlushErrors, 1)
+
+		if tb.retryPolicy == nil || !tb.retryPolicy.ShouldRetry(err, time.Since(start)) {
+			if tb.onError != nil {
+				tb.onError(err, p[sent:], attempt)
+			}
+			return
+		}
+
+		ctx := tb.shutdownCtx.Load().(context.Context)
+		select {
+		case <-time.After(tb.retryPolicy.Backoff(attempt)):
+		case <-ctx.Done():
+			if tb.onError != nil {
+				tb.onError(err, p[sent:], attempt)
+			}
+			return
+		}
+	}
 }
 
-func (tb *Buffer) getBuffer() *internal.Buffer {
-	b, alloc := tb.pool.Get()
+func (tb *Buffer) getBuffer() *bufpool.Buffer {
+	b, alloc := tb.pool.Get(tb.bufSize)
 	if alloc {
 		tb.stats.BufferAllocs++
 	}
@@ -204,6 +509,12 @@ type Stats struct {
 	BufferAllocs int32
 	// Count of errors obtained trying to write to the underlying writer
 	FlushErrors int32
+	// BatchedFlushes is the number of flushes that coalesced more than
+	// one buffer into a single underlying write
+	BatchedFlushes int32
+	// VectorBytes is the total number of bytes written through flushes,
+	// batched or not
+	VectorBytes int64
 }
 
 // Stats returns a copy of the current writer stats
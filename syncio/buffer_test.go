@@ -0,0 +1,45 @@
+package syncio
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// TestBuffer_CloseRacesTicker exercises Close concurrently with writes while
+// a flush-interval ticker is running; run with -race, it catches unsynchronized
+// access to the buffer and its flush queue between the ticker goroutine, the
+// writing goroutine, and Close.
+func TestBuffer_CloseRacesTicker(t *testing.T) {
+	var mu sync.Mutex
+	var written int
+
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		written += len(p)
+		mu.Unlock()
+		return len(p), nil
+	})
+	tb := NewBuffer(w, SetBufferSize(16), SetFlushInterval(time.Millisecond))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tb.Write([]byte("x"))
+		}
+	}()
+	wg.Wait()
+
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := tb.Write([]byte("x")); err != ErrWriteOnClosed {
+		t.Fatalf("Write after Close = %v, want ErrWriteOnClosed", err)
+	}
+}
@@ -0,0 +1,119 @@
+package syncio
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/bufpool"
+)
+
+// partialWriter fails once its Write calls have delivered failAfter bytes in
+// total, writing only the bytes up to that point before erroring; every
+// subsequent call succeeds. It's used to simulate a multi-buffer write that
+// fails partway through.
+type partialWriter struct {
+	mu        sync.Mutex
+	written   []byte
+	failAfter int
+	failed    bool
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.failed && len(w.written)+len(p) > w.failAfter {
+		n := w.failAfter - len(w.written)
+		if n < 0 {
+			n = 0
+		}
+		w.written = append(w.written, p[:n]...)
+		w.failed = true
+		return n, errors.New("boom")
+	}
+	w.written = append(w.written, p...)
+	return len(p), nil
+}
+
+// TestBuffer_WriteBatchRetryDoesNotDuplicate checks that retrying a batch
+// write that failed partway through resumes after the bytes already
+// delivered, instead of resending the whole batch and duplicating them.
+func TestBuffer_WriteBatchRetryDoesNotDuplicate(t *testing.T) {
+	w := &partialWriter{failAfter: 5}
+	tb := NewBuffer(w, SetBufferSize(4), SetBufferPoolSize(4),
+		SetRetryPolicy(RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+
+	b1 := tb.getBuffer()
+	b1.Write([]byte("abcd"))
+	b2 := tb.getBuffer()
+	b2.Write([]byte("efgh"))
+
+	tb.writeBatch([]*bufpool.Buffer{b1, b2})
+
+	if want := "abcdefgh"; string(w.written) != want {
+		t.Fatalf("written = %q, want %q", w.written, want)
+	}
+}
+
+// TestBuffer_WriteDoesNotBlockOnBackedUpRetry checks that Write never blocks
+// on flush()'s handoff to the flush queue, even once several flushes have
+// piled up behind a retry that's sleeping on a long backoff. flush() always
+// runs with bufmu held, so a blocking handoff there would stall every
+// concurrent Write for as long as the retry takes.
+func TestBuffer_WriteDoesNotBlockOnBackedUpRetry(t *testing.T) {
+	w := writerFunc(func(p []byte) (int, error) { return 0, errors.New("down") })
+	tb := NewBuffer(w, SetBufferSize(1), SetBufferPoolSize(1),
+		SetRetryPolicy(RetryPolicy{InitialInterval: time.Hour, MaxInterval: time.Hour}))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := tb.Write([]byte("x")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("5 Write calls took %v, want well under the retry backoff: a backed-up retry must never block Write", elapsed)
+	}
+}
+
+// TestBuffer_WriteDirectRetryDoesNotDuplicate is TestBuffer_WriteBatchRetryDoesNotDuplicate's
+// counterpart for the oversized-write bypass path, which has its own retry loop.
+func TestBuffer_WriteDirectRetryDoesNotDuplicate(t *testing.T) {
+	w := &partialWriter{failAfter: 5}
+	tb := NewBuffer(w, SetBufferSize(4),
+		SetRetryPolicy(RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+
+	tb.wg.Add(1)
+	tb.writeDirect([]byte("abcdefghij"))
+
+	if want := "abcdefghij"; string(w.written) != want {
+		t.Fatalf("written = %q, want %q", w.written, want)
+	}
+}
+
+// TestBuffer_WriteDirectReportsFailureToOnError checks that a give-up on the
+// oversized-write path reports the payload to OnError instead of dropping it
+// silently, as giveUp already does for writeBatch.
+func TestBuffer_WriteDirectReportsFailureToOnError(t *testing.T) {
+	boom := errors.New("boom")
+	w := writerFunc(func(p []byte) (int, error) { return 0, boom })
+
+	var gotErr error
+	var gotPayload []byte
+	tb := NewBuffer(w, SetBufferSize(4), SetOnError(func(err error, payload []byte, attempt int) {
+		gotErr = err
+		gotPayload = append([]byte(nil), payload...)
+	}))
+
+	p := []byte("abcdefgh")
+	tb.wg.Add(1)
+	tb.writeDirect(p)
+
+	if gotErr != boom {
+		t.Fatalf("onError err = %v, want %v", gotErr, boom)
+	}
+	if string(gotPayload) != string(p) {
+		t.Fatalf("onError payload = %q, want %q", gotPayload, p)
+	}
+}